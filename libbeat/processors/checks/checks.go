@@ -19,6 +19,8 @@ package checks
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/processors"
@@ -26,14 +28,66 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 )
 
-// ConfigChecked returns a wrapper that will validate the configuration using
-// the passed checks before invoking the original constructor.
+// checksLogger is used by checks, like DeprecatedField, that report a
+// problem without failing configuration validation, since Check has no
+// logger of its own to call into.
+var checksLogger = logp.NewLogger("processors.checks")
+
+// kind identifies the check constructors that Registry.JSONSchema knows how
+// to render. Checks built from any other constructor still validate
+// configurations normally; they just don't contribute schema properties.
+type kind uint8
+
+const (
+	kindOther kind = iota
+	kindRequire
+	kindAllow
+	kindMutex
+)
+
+// Check is a single configuration constraint produced by one of this
+// package's constructors, such as RequireFields or AllowedFields. It
+// validates a *config.C like a plain func, but also carries enough of a
+// description of itself for a Registry to render as JSON Schema.
+type Check struct {
+	validate func(*config.C) error
+	kind     kind
+	fields   []string
+}
+
+func newCheck(k kind, fields []string, validate func(*config.C) error) Check {
+	return Check{validate: validate, kind: k, fields: fields}
+}
+
+// Func adapts a hand-rolled validator to a Check so it can be passed to
+// ConfigChecked alongside this package's own combinators. A Func-wrapped
+// check still validates configurations normally; like RequiresIf or
+// ConflictsWith, it just doesn't describe a static set of properties, so it
+// doesn't contribute anything to Registry.JSONSchema.
+func Func(fn func(*config.C) error) Check {
+	return newCheck(kindOther, nil, fn)
+}
+
+// CheckedConstructor is the result of wrapping a processors.Constructor with
+// ConfigChecked: the wrapped constructor itself, plus the exact checks it
+// validates configurations with. Register reads Checks from here rather
+// than taking its own list, so the rules it renders as JSON Schema can
+// never drift from the rules actually enforced.
+type CheckedConstructor struct {
+	Constructor processors.Constructor
+	Checks      []Check
+}
+
+// ConfigChecked returns a CheckedConstructor that will validate the
+// configuration using the passed checks before invoking the original
+// constructor. Register it with Register to also make the processor's
+// configuration shape discoverable as JSON Schema.
 func ConfigChecked(
 	constr processors.Constructor,
-	checks ...func(*config.C) error,
-) processors.Constructor {
+	checks ...Check,
+) CheckedConstructor {
 	validator := checkAll(checks...)
-	return func(cfg *config.C, log *logp.Logger) (beat.Processor, error) {
+	wrapped := func(cfg *config.C, log *logp.Logger) (beat.Processor, error) {
 		err := validator(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("%w in %v", err, cfg.Path())
@@ -41,34 +95,71 @@ func ConfigChecked(
 
 		return constr(cfg, log)
 	}
+	return CheckedConstructor{Constructor: wrapped, Checks: checks}
 }
 
-func checkAll(checks ...func(*config.C) error) func(*config.C) error {
+func checkAll(checks ...Check) func(*config.C) error {
 	return func(c *config.C) error {
+		var errs Errors
 		for _, check := range checks {
-			if err := check(c); err != nil {
-				return err
-			}
+			errs.Add(check.validate(c))
 		}
+		return errs.Err()
+	}
+}
+
+// Errors aggregates every check violation found for a single configuration,
+// instead of stopping at the first one. This lets users fix every reported
+// issue in one iteration rather than playing whack-a-mole with their config.
+type Errors struct {
+	errs []error
+}
+
+// Add appends err to the aggregate if it is non-nil.
+func (e *Errors) Add(err error) {
+	if err != nil {
+		e.errs = append(e.errs, err)
+	}
+}
+
+// Err returns the aggregated error, or nil if nothing was added.
+func (e *Errors) Err() error {
+	if len(e.errs) == 0 {
 		return nil
 	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *Errors) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is/errors.As to see through the aggregate to the
+// individual check errors it contains.
+func (e *Errors) Unwrap() []error {
+	return e.errs
 }
 
 // RequireFields checks that the required fields are present in the configuration.
-func RequireFields(fields ...string) func(*config.C) error {
-	return func(cfg *config.C) error {
+func RequireFields(fields ...string) Check {
+	return newCheck(kindRequire, fields, func(cfg *config.C) error {
 		for _, field := range fields {
 			if !cfg.HasField(field) {
 				return fmt.Errorf("missing %v option", field)
 			}
 		}
 		return nil
-	}
+	})
 }
 
 // AllowedFields checks that only allowed fields are used in the configuration.
-func AllowedFields(fields ...string) func(*config.C) error {
-	return func(cfg *config.C) error {
+func AllowedFields(fields ...string) Check {
+	return newCheck(kindAllow, fields, func(cfg *config.C) error {
 		for _, field := range cfg.GetFields() {
 			found := false
 			for _, allowed := range fields {
@@ -83,14 +174,14 @@ func AllowedFields(fields ...string) func(*config.C) error {
 			}
 		}
 		return nil
-	}
+	})
 }
 
 // MutuallyExclusiveRequiredFields checks that only one of the given
 // fields is used at the same time. It is an error for none of the fields to be
 // present.
-func MutuallyExclusiveRequiredFields(fields ...string) func(*config.C) error {
-	return func(cfg *config.C) error {
+func MutuallyExclusiveRequiredFields(fields ...string) Check {
+	return newCheck(kindMutex, fields, func(cfg *config.C) error {
 		var foundField string
 		for _, field := range cfg.GetFields() {
 			for _, f := range fields {
@@ -108,5 +199,142 @@ func MutuallyExclusiveRequiredFields(fields ...string) func(*config.C) error {
 			return fmt.Errorf("missing option, select one from %v", fields)
 		}
 		return nil
+	})
+}
+
+// RequiresIf checks that requiredFields are all present whenever field is
+// set to value. It is a no-op if field is absent or set to anything else.
+func RequiresIf(field string, value interface{}, requiredFields ...string) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		if !cfg.HasField(field) {
+			return nil
+		}
+
+		actual, err := fieldValue(cfg, field)
+		if err != nil {
+			return fmt.Errorf("failed to read %v option: %w", field, err)
+		}
+		if !reflect.DeepEqual(actual, value) {
+			return nil
+		}
+
+		return RequireFields(requiredFields...).validate(cfg)
+	})
+}
+
+// ConflictsWith checks that a and b are not both present in the
+// configuration at the same time.
+func ConflictsWith(a, b string) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		if cfg.HasField(a) && cfg.HasField(b) {
+			return fmt.Errorf("%v and %v are mutually exclusive", a, b)
+		}
+		return nil
+	})
+}
+
+// AtLeastOneOf checks that at least one of fields is present in the
+// configuration.
+func AtLeastOneOf(fields ...string) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		for _, field := range fields {
+			if cfg.HasField(field) {
+				return nil
+			}
+		}
+		return fmt.Errorf("missing option, select at least one of %v", fields)
+	})
+}
+
+// AtMostOneOf checks that no more than one of fields is present in the
+// configuration. Unlike MutuallyExclusiveRequiredFields, it is not an error
+// for none of them to be present.
+func AtMostOneOf(fields ...string) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		var found []string
+		for _, field := range fields {
+			if cfg.HasField(field) {
+				found = append(found, field)
+			}
+		}
+		if len(found) > 1 {
+			return fmt.Errorf("%v are mutually exclusive", found)
+		}
+		return nil
+	})
+}
+
+// FieldKind identifies the broad shape a configuration value must have for
+// FieldType to accept it.
+type FieldKind uint8
+
+const (
+	// ScalarField matches any value that isn't a list or a map.
+	ScalarField FieldKind = iota
+	// ListField matches a list value.
+	ListField
+	// MapField matches a dictionary value.
+	MapField
+)
+
+func (k FieldKind) String() string {
+	switch k {
+	case ListField:
+		return "list"
+	case MapField:
+		return "map"
+	default:
+		return "scalar"
+	}
+}
+
+// FieldType checks that, if present, field holds a value of the given kind.
+func FieldType(field string, want FieldKind) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		if !cfg.HasField(field) {
+			return nil
+		}
+
+		actual, err := fieldValue(cfg, field)
+		if err != nil {
+			return fmt.Errorf("failed to read %v option: %w", field, err)
+		}
+
+		var actualKind FieldKind
+		switch actual.(type) {
+		case []interface{}:
+			actualKind = ListField
+		case map[string]interface{}:
+			actualKind = MapField
+		default:
+			actualKind = ScalarField
+		}
+
+		if actualKind != want {
+			return fmt.Errorf("%v must be a %v, found a %v", field, want, actualKind)
+		}
+		return nil
+	})
+}
+
+// DeprecatedField logs a deprecation warning when field is present instead
+// of rejecting the configuration, so processor authors can phase out a field
+// in favor of replacement without breaking existing configurations.
+func DeprecatedField(field, replacement string) Check {
+	return newCheck(kindOther, nil, func(cfg *config.C) error {
+		if cfg.HasField(field) {
+			checksLogger.Warnf("%v option is deprecated, use %v instead", field, replacement)
+		}
+		return nil
+	})
+}
+
+// fieldValue returns the unpacked value of a top-level field, or nil if it
+// isn't set.
+func fieldValue(cfg *config.C, field string) (interface{}, error) {
+	var values map[string]interface{}
+	if err := cfg.Unpack(&values); err != nil {
+		return nil, err
 	}
+	return values[field], nil
 }
@@ -0,0 +1,215 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package checks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func mustNewConfig(t *testing.T, in map[string]interface{}) *config.C {
+	t.Helper()
+	cfg, err := config.NewConfigFrom(in)
+	if err != nil {
+		t.Fatalf("failed to build config: %v", err)
+	}
+	return cfg
+}
+
+func TestRequireFields(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{"a": 1})
+
+	if err := RequireFields("a").validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := RequireFields("b").validate(cfg); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+}
+
+func TestAllowedFields(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{"a": 1, "b": 2})
+
+	if err := AllowedFields("a", "b").validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := AllowedFields("a").validate(cfg); err == nil {
+		t.Error("expected an error for an unexpected field")
+	}
+}
+
+func TestMutuallyExclusiveRequiredFields(t *testing.T) {
+	none := mustNewConfig(t, map[string]interface{}{})
+	if err := MutuallyExclusiveRequiredFields("a", "b").validate(none); err == nil {
+		t.Error("expected an error when none of the fields are set")
+	}
+
+	one := mustNewConfig(t, map[string]interface{}{"a": 1})
+	if err := MutuallyExclusiveRequiredFields("a", "b").validate(one); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	both := mustNewConfig(t, map[string]interface{}{"a": 1, "b": 2})
+	if err := MutuallyExclusiveRequiredFields("a", "b").validate(both); err == nil {
+		t.Error("expected an error when both fields are set")
+	}
+}
+
+func TestRequiresIf(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{"type": "foo"})
+
+	if err := RequiresIf("type", "bar", "extra").validate(cfg); err != nil {
+		t.Errorf("expected no error when field doesn't match value, got %v", err)
+	}
+	if err := RequiresIf("type", "foo", "extra").validate(cfg); err == nil {
+		t.Error("expected an error for a missing required field")
+	}
+
+	withExtra := mustNewConfig(t, map[string]interface{}{"type": "foo", "extra": 1})
+	if err := RequiresIf("type", "foo", "extra").validate(withExtra); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestConflictsWith(t *testing.T) {
+	both := mustNewConfig(t, map[string]interface{}{"a": 1, "b": 2})
+	if err := ConflictsWith("a", "b").validate(both); err == nil {
+		t.Error("expected an error when both fields are set")
+	}
+
+	one := mustNewConfig(t, map[string]interface{}{"a": 1})
+	if err := ConflictsWith("a", "b").validate(one); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestAtLeastOneOf(t *testing.T) {
+	none := mustNewConfig(t, map[string]interface{}{})
+	if err := AtLeastOneOf("a", "b").validate(none); err == nil {
+		t.Error("expected an error when none are set")
+	}
+
+	one := mustNewConfig(t, map[string]interface{}{"a": 1})
+	if err := AtLeastOneOf("a", "b").validate(one); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestAtMostOneOf(t *testing.T) {
+	none := mustNewConfig(t, map[string]interface{}{})
+	if err := AtMostOneOf("a", "b").validate(none); err != nil {
+		t.Errorf("expected no error when none are set, got %v", err)
+	}
+
+	both := mustNewConfig(t, map[string]interface{}{"a": 1, "b": 2})
+	if err := AtMostOneOf("a", "b").validate(both); err == nil {
+		t.Error("expected an error when both are set")
+	}
+}
+
+func TestFieldType(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{
+		"scalar":  "x",
+		"list":    []interface{}{1, 2},
+		"mapping": map[string]interface{}{"x": 1},
+	})
+
+	if err := FieldType("scalar", ScalarField).validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := FieldType("list", ListField).validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := FieldType("mapping", MapField).validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := FieldType("list", MapField).validate(cfg); err == nil {
+		t.Error("expected an error for a mismatched kind")
+	}
+}
+
+func TestDeprecatedFieldDoesNotError(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{"old": 1})
+	if err := DeprecatedField("old", "new").validate(cfg); err != nil {
+		t.Errorf("DeprecatedField should never fail validation, got %v", err)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{"a": 1})
+	custom := Func(func(cfg *config.C) error {
+		if cfg.HasField("a") {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	if err := custom.validate(cfg); err == nil {
+		t.Error("expected the hand-rolled validator's error to propagate")
+	}
+}
+
+func TestErrorsAggregatesAllChecks(t *testing.T) {
+	cfg := mustNewConfig(t, map[string]interface{}{})
+
+	err := checkAll(RequireFields("a"), RequireFields("b"))(cfg)
+	if err == nil {
+		t.Fatal("expected an aggregated error")
+	}
+
+	var agg *Errors
+	if !errors.As(err, &agg) {
+		t.Fatalf("expected *Errors, got %T", err)
+	}
+	if len(agg.errs) != 2 {
+		t.Fatalf("expected both checks to be reported, got %d errors: %v", len(agg.errs), agg)
+	}
+}
+
+func TestConfigCheckedRunsChecksBeforeConstructor(t *testing.T) {
+	called := false
+	constr := func(cfg *config.C, log *logp.Logger) (beat.Processor, error) {
+		called = true
+		return nil, nil
+	}
+
+	checked := ConfigChecked(constr, RequireFields("a"))
+	if len(checked.Checks) != 1 {
+		t.Fatalf("expected ConfigChecked to record its checks, got %d", len(checked.Checks))
+	}
+
+	missing := mustNewConfig(t, map[string]interface{}{})
+	if _, err := checked.Constructor(missing, nil); err == nil {
+		t.Error("expected a validation error")
+	}
+	if called {
+		t.Error("constructor should not run when validation fails")
+	}
+
+	present := mustNewConfig(t, map[string]interface{}{"a": 1})
+	if _, err := checked.Constructor(present, nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if !called {
+		t.Error("constructor should run when validation succeeds")
+	}
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package checks
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/processors"
+)
+
+// Register registers constr.Constructor under name like processors.Register
+// does, and additionally records constr.Checks against DefaultRegistry so
+// that DefaultRegistry.JSONSchema can describe name's accepted
+// configuration. Since constr.Checks is exactly what ConfigChecked
+// validated constr.Constructor with, the emitted schema can't drift from
+// the rules actually enforced. Processors validated with ConfigChecked
+// should use this instead of processors.Register, passing it the
+// CheckedConstructor ConfigChecked returned.
+//
+// Register lives in this package rather than processors so that checks,
+// which already imports processors for the Constructor type, doesn't import
+// it back.
+func Register(name string, constr CheckedConstructor) error {
+	DefaultRegistry.Add(name, constr.Checks...)
+	return processors.Register(name, constr.Constructor)
+}
+
+// DefaultRegistry is the Registry that Register records checks into.
+var DefaultRegistry = &Registry{}
+
+// Registry records the checks each processor was built with, so its
+// accepted configuration can later be described as JSON Schema.
+type Registry struct {
+	mu    sync.Mutex
+	specs map[string][]Check
+}
+
+// Add records checks as the configuration constraints for the processor
+// named name, replacing anything previously recorded under that name.
+func (r *Registry) Add(name string, checks ...Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.specs == nil {
+		r.specs = make(map[string][]Check)
+	}
+	r.specs[name] = checks
+}
+
+// schemaDoc is the top-level JSON Schema document produced by JSONSchema:
+// one property per registered processor, keyed by processor name.
+type schemaDoc struct {
+	Schema     string                `json:"$schema"`
+	Type       string                `json:"type"`
+	Properties map[string]fieldSpecs `json:"properties"`
+}
+
+// fieldSpecs describes a single processor's accepted configuration.
+type fieldSpecs struct {
+	Type                 string                `json:"type"`
+	Properties           map[string]struct{}   `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *bool                 `json:"additionalProperties,omitempty"`
+	OneOf                []map[string][]string `json:"oneOf,omitempty"`
+}
+
+// JSONSchema renders every processor recorded with Add into a single JSON
+// Schema document: RequireFields contributes to "required",
+// AllowedFields disallows additional properties, and
+// MutuallyExclusiveRequiredFields becomes a "oneOf" of single-field
+// "required" clauses. Checks built from any other constructor (RequiresIf,
+// ConflictsWith, ...) still validate configurations but aren't represented
+// here, since they don't describe a static set of properties.
+func (r *Registry) JSONSchema() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc := schemaDoc{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Type:       "object",
+		Properties: make(map[string]fieldSpecs, len(r.specs)),
+	}
+
+	names := make([]string, 0, len(r.specs))
+	for name := range r.specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		doc.Properties[name] = processorSchema(r.specs[name])
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func processorSchema(checks []Check) fieldSpecs {
+	spec := fieldSpecs{
+		Type:       "object",
+		Properties: map[string]struct{}{},
+	}
+
+	disallowAdditional := false
+	for _, c := range checks {
+		switch c.kind {
+		case kindRequire:
+			spec.Required = append(spec.Required, c.fields...)
+			addProperties(spec.Properties, c.fields)
+		case kindAllow:
+			disallowAdditional = true
+			addProperties(spec.Properties, c.fields)
+		case kindMutex:
+			addProperties(spec.Properties, c.fields)
+			for _, f := range c.fields {
+				spec.OneOf = append(spec.OneOf, map[string][]string{"required": {f}})
+			}
+		}
+	}
+
+	if disallowAdditional {
+		spec.AdditionalProperties = boolPtr(false)
+	}
+
+	return spec
+}
+
+func addProperties(properties map[string]struct{}, fields []string) {
+	for _, f := range fields {
+		properties[f] = struct{}{}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package checks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRegistryJSONSchema(t *testing.T) {
+	reg := &Registry{}
+	reg.Add("drop_fields", RequireFields("fields"), AllowedFields("fields", "ignore_missing"))
+	reg.Add("rename", MutuallyExclusiveRequiredFields("from", "fields"))
+
+	out, err := reg.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var doc struct {
+		Properties map[string]struct {
+			Required             []string               `json:"required"`
+			AdditionalProperties *bool                  `json:"additionalProperties"`
+			OneOf                []map[string][]string  `json:"oneOf"`
+			Properties           map[string]struct{}    `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+
+	dropFields, ok := doc.Properties["drop_fields"]
+	if !ok {
+		t.Fatal("expected drop_fields to be described")
+	}
+	if len(dropFields.Required) != 1 || dropFields.Required[0] != "fields" {
+		t.Errorf("expected fields to be required, got %v", dropFields.Required)
+	}
+	if dropFields.AdditionalProperties == nil || *dropFields.AdditionalProperties {
+		t.Error("expected additionalProperties to be false once AllowedFields is used")
+	}
+	if _, ok := dropFields.Properties["ignore_missing"]; !ok {
+		t.Error("expected ignore_missing to be listed as a property")
+	}
+
+	rename, ok := doc.Properties["rename"]
+	if !ok {
+		t.Fatal("expected rename to be described")
+	}
+	if len(rename.OneOf) != 2 {
+		t.Errorf("expected one oneOf entry per mutually exclusive field, got %d", len(rename.OneOf))
+	}
+}
+
+func TestRegistryJSONSchemaIgnoresNonSchemaChecks(t *testing.T) {
+	reg := &Registry{}
+	reg.Add("noop", ConflictsWith("a", "b"), RequiresIf("type", "x", "y"))
+
+	out, err := reg.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	if _, ok := doc.Properties["noop"]; !ok {
+		t.Fatal("expected the noop processor to still appear in the schema")
+	}
+}
+
+func TestRegistryAddReplacesPreviousChecks(t *testing.T) {
+	reg := &Registry{}
+	reg.Add("p", RequireFields("a"))
+	reg.Add("p", RequireFields("b"))
+
+	out, err := reg.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var doc struct {
+		Properties map[string]struct {
+			Required []string `json:"required"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+
+	required := doc.Properties["p"].Required
+	if len(required) != 1 || required[0] != "b" {
+		t.Errorf("expected the second Add to replace the first, got %v", required)
+	}
+}
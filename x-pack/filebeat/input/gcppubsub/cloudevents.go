@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// cePrefix is the attribute prefix the CloudEvents Pub/Sub protocol binding
+// uses to carry CloudEvents context attributes in binary mode.
+// See https://github.com/cloudevents/spec/blob/main/cloudevents/bindings/pubsub-protocol-binding.md
+const cePrefix = "ce-"
+
+// structuredContentType is the attribute that marks a Pub/Sub message as
+// carrying a full CloudEvent JSON document in structured mode.
+const structuredContentType = "application/cloudevents+json"
+
+// makeCloudEvent decodes msg per the CloudEvents Pub/Sub protocol binding,
+// in either structured mode (the whole payload is a CloudEvents JSON
+// document) or binary mode (context attributes are carried as ce- prefixed
+// Pub/Sub attributes and the payload is the event data).
+func makeCloudEvent(topicID string, msg *pubsub.Message) (beat.Event, error) {
+	if msg.Attributes["content-type"] == structuredContentType {
+		return makeStructuredCloudEvent(topicID, msg)
+	}
+	return makeBinaryCloudEvent(topicID, msg)
+}
+
+func makeBinaryCloudEvent(topicID string, msg *pubsub.Message) (beat.Event, error) {
+	ce := mapstr.M{}
+	labels := mapstr.M{}
+	for key, value := range msg.Attributes {
+		if !strings.HasPrefix(key, cePrefix) {
+			labels[key] = value
+			continue
+		}
+		ce[strings.TrimPrefix(key, cePrefix)] = value
+	}
+
+	id := topicID + "-" + msg.ID
+	event := beat.Event{
+		Timestamp: msg.PublishTime.UTC(),
+		Fields: mapstr.M{
+			"event": mapstr.M{
+				"id":      id,
+				"created": time.Now().UTC(),
+			},
+			"cloudevents": ce,
+		},
+		Private: msg,
+	}
+	event.SetID(id)
+
+	if len(labels) > 0 {
+		event.Fields["labels"] = labels
+	}
+
+	contentType := msg.Attributes["ce-datacontenttype"]
+	setCloudEventData(event.Fields, contentType, msg.Data)
+
+	return event, nil
+}
+
+func makeStructuredCloudEvent(topicID string, msg *pubsub.Message) (beat.Event, error) {
+	var doc struct {
+		ID              string          `json:"id"`
+		Source          string          `json:"source"`
+		Type            string          `json:"type"`
+		SpecVersion     string          `json:"specversion"`
+		DataContentType string          `json:"datacontenttype"`
+		Subject         string          `json:"subject"`
+		Time            time.Time       `json:"time"`
+		Data            json.RawMessage `json:"data"`
+		DataBase64      string          `json:"data_base64"`
+	}
+	if err := json.Unmarshal(msg.Data, &doc); err != nil {
+		return beat.Event{}, fmt.Errorf("failed to decode structured CloudEvent: %w", err)
+	}
+
+	id := topicID + "-" + msg.ID
+	event := beat.Event{
+		Timestamp: msg.PublishTime.UTC(),
+		Fields: mapstr.M{
+			"event": mapstr.M{
+				"id":      id,
+				"created": time.Now().UTC(),
+			},
+			"cloudevents": mapstr.M{
+				"id":              doc.ID,
+				"source":          doc.Source,
+				"type":            doc.Type,
+				"specversion":     doc.SpecVersion,
+				"datacontenttype": doc.DataContentType,
+				"subject":         doc.Subject,
+				"time":            doc.Time,
+			},
+		},
+		Private: msg,
+	}
+	event.SetID(id)
+
+	if len(msg.Attributes) > 0 {
+		event.Fields["labels"] = msg.Attributes
+	}
+
+	if doc.DataBase64 != "" {
+		data, err := base64.StdEncoding.DecodeString(doc.DataBase64)
+		if err != nil {
+			return beat.Event{}, fmt.Errorf("failed to decode structured CloudEvent data_base64: %w", err)
+		}
+		setCloudEventData(event.Fields, doc.DataContentType, data)
+	} else if len(doc.Data) > 0 {
+		data := []byte(doc.Data)
+		// A non-JSON payload carried as a JSON string is stored unquoted.
+		var s string
+		if !strings.Contains(doc.DataContentType, "json") && json.Unmarshal(doc.Data, &s) == nil {
+			data = []byte(s)
+		}
+		setCloudEventData(event.Fields, doc.DataContentType, data)
+	}
+
+	return event, nil
+}
+
+// setCloudEventData stores the event payload under "data" when contentType
+// indicates textual/JSON content, or "data_base64" otherwise, matching the
+// CloudEvents Pub/Sub protocol binding's own field naming.
+func setCloudEventData(fields mapstr.M, contentType string, data []byte) {
+	if strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") {
+		fields["data"] = string(data)
+		return
+	}
+	fields["data_base64"] = base64.StdEncoding.EncodeToString(data)
+}
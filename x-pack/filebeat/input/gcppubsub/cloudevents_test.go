@@ -0,0 +1,186 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestMakeBinaryCloudEvent(t *testing.T) {
+	msg := &pubsub.Message{
+		ID:          "m1",
+		PublishTime: time.Unix(0, 0).UTC(),
+		Attributes: map[string]string{
+			"ce-id":              "123",
+			"ce-source":          "//pubsub.googleapis.com/projects/p/topics/t",
+			"ce-type":            "google.cloud.pubsub.topic.v1.messagePublished",
+			"ce-specversion":     "1.0",
+			"ce-datacontenttype": "application/json",
+			"not-a-ce-field":     "ignored",
+		},
+		Data: []byte(`{"hello":"world"}`),
+	}
+
+	event, err := makeCloudEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("makeCloudEvent failed: %v", err)
+	}
+
+	ce, ok := event.Fields["cloudevents"].(mapstr.M)
+	if !ok {
+		t.Fatalf("expected cloudevents field to be a mapstr.M, got %T", event.Fields["cloudevents"])
+	}
+	if ce["id"] != "123" {
+		t.Errorf("expected ce id 123, got %v", ce["id"])
+	}
+	if _, ok := ce["not-a-ce-field"]; ok {
+		t.Error("attributes without the ce- prefix should not leak into the cloudevents fields")
+	}
+
+	if data := event.Fields["data"]; data != `{"hello":"world"}` {
+		t.Errorf("expected a JSON content-type to produce a data field, got %v", data)
+	}
+	if _, ok := event.Fields["message"]; ok {
+		t.Error("expected the CloudEvents mapping to replace the ad-hoc message field, not add to it")
+	}
+
+	labels, ok := event.Fields["labels"].(mapstr.M)
+	if !ok {
+		t.Fatalf("expected labels field to be a mapstr.M, got %T", event.Fields["labels"])
+	}
+	if labels["not-a-ce-field"] != "ignored" {
+		t.Errorf("expected non-ce attributes to surface as labels, got %v", labels)
+	}
+	if _, ok := labels["ce-id"]; ok {
+		t.Error("expected ce- attributes not to be duplicated into labels")
+	}
+}
+
+func TestMakeBinaryCloudEventNonTextData(t *testing.T) {
+	msg := &pubsub.Message{
+		ID:          "m2",
+		PublishTime: time.Unix(0, 0).UTC(),
+		Attributes: map[string]string{
+			"ce-id":              "124",
+			"ce-datacontenttype": "application/octet-stream",
+		},
+		Data: []byte{0x00, 0x01, 0x02},
+	}
+
+	event, err := makeCloudEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("makeCloudEvent failed: %v", err)
+	}
+
+	if _, ok := event.Fields["data"]; ok {
+		t.Error("expected binary content-type to avoid the data field")
+	}
+	if _, ok := event.Fields["data_base64"]; !ok {
+		t.Error("expected binary content-type to produce a data_base64 field")
+	}
+}
+
+func TestMakeStructuredCloudEvent(t *testing.T) {
+	msg := &pubsub.Message{
+		ID:          "m3",
+		PublishTime: time.Unix(0, 0).UTC(),
+		Attributes: map[string]string{
+			"content-type": structuredContentType,
+		},
+		Data: []byte(`{
+			"id": "456",
+			"source": "//pubsub.googleapis.com/projects/p/topics/t",
+			"type": "google.cloud.pubsub.topic.v1.messagePublished",
+			"specversion": "1.0",
+			"datacontenttype": "text/plain",
+			"data": "hello"
+		}`),
+	}
+
+	event, err := makeCloudEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("makeCloudEvent failed: %v", err)
+	}
+
+	ce, ok := event.Fields["cloudevents"].(mapstr.M)
+	if !ok {
+		t.Fatalf("expected cloudevents field to be a mapstr.M, got %T", event.Fields["cloudevents"])
+	}
+	if ce["id"] != "456" {
+		t.Errorf("expected ce id 456, got %v", ce["id"])
+	}
+	if data := event.Fields["data"]; data != "hello" {
+		t.Errorf("expected data %q, got %v", "hello", data)
+	}
+}
+
+func TestMakeStructuredCloudEventDataBase64(t *testing.T) {
+	msg := &pubsub.Message{
+		ID:          "m4",
+		PublishTime: time.Unix(0, 0).UTC(),
+		Attributes: map[string]string{
+			"content-type": structuredContentType,
+		},
+		Data: []byte(`{
+			"id": "789",
+			"datacontenttype": "application/octet-stream",
+			"data_base64": "AAEC"
+		}`),
+	}
+
+	event, err := makeCloudEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("makeCloudEvent failed: %v", err)
+	}
+
+	if _, ok := event.Fields["data"]; ok {
+		t.Error("expected binary content-type to avoid the data field")
+	}
+	if data := event.Fields["data_base64"]; data != "AAEC" {
+		t.Errorf("expected the decoded and re-encoded payload to round-trip, got %v", data)
+	}
+}
+
+func TestMakeStructuredCloudEventInvalidJSON(t *testing.T) {
+	msg := &pubsub.Message{
+		ID:         "m5",
+		Attributes: map[string]string{"content-type": structuredContentType},
+		Data:       []byte("not json"),
+	}
+
+	if _, err := makeCloudEvent("topic123", msg); err == nil {
+		t.Error("expected an error decoding a malformed structured CloudEvent")
+	}
+}
+
+func TestBuildEventDispatchesOnFormat(t *testing.T) {
+	msg := &pubsub.Message{ID: "m6", PublishTime: time.Unix(0, 0).UTC(), Data: []byte("x")}
+
+	in := &pubsubInput{}
+	in.Format = formatCloudEvents
+	event, err := in.buildEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("buildEvent failed: %v", err)
+	}
+	if _, ok := event.Fields["cloudevents"]; !ok {
+		t.Error("expected format: cloudevents to produce a cloudevents field")
+	}
+
+	in.Format = formatDefault
+	event, err = in.buildEvent("topic123", msg)
+	if err != nil {
+		t.Fatalf("buildEvent failed: %v", err)
+	}
+	if _, ok := event.Fields["cloudevents"]; ok {
+		t.Error("expected the default format to use the ad-hoc mapping, not CloudEvents")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// formatDefault is the ad-hoc event.id/message/labels mapping produced by
+// makeEvent.
+const formatDefault = ""
+
+// formatCloudEvents decodes messages per the CloudEvents Pub/Sub protocol
+// binding instead of the default ad-hoc mapping.
+const formatCloudEvents = "cloudevents"
+
+type config struct {
+	Type            string             `config:"type"`
+	ProjectID       string             `config:"project_id" validate:"required"`
+	Topic           string             `config:"topic" validate:"required"`
+	Subscription    SubscriptionConfig `config:"subscription" validate:"required"`
+	CredentialsFile string             `config:"credentials_file"`
+	CredentialsJSON []byte             `config:"credentials_json"`
+	AlternativeHost string             `config:"alternative_host"`
+
+	// Push holds the configuration for running the input as an HTTP push
+	// endpoint instead of pulling messages with sub.Receive. It is left
+	// unset (Enabled == false) for the default pull-based mode.
+	Push PushConfig `config:"push"`
+
+	// Format selects how the raw Pub/Sub message is turned into a
+	// beat.Event. The default ("") uses the ad-hoc event.id/message/labels
+	// mapping; "cloudevents" decodes the message per the CloudEvents
+	// Pub/Sub protocol binding instead.
+	Format string `config:"format"`
+
+	// Delivery configures the retry/dead-letter policy applied to messages
+	// that repeatedly fail to reach the output.
+	Delivery DeliveryConfig `config:"delivery"`
+
+	Transport httpcommon.HTTPTransportSettings `config:",inline"`
+}
+
+// SubscriptionConfig configures the Pub/Sub subscription used to pull
+// messages from the configured topic.
+type SubscriptionConfig struct {
+	Name                   string `config:"name" validate:"required"`
+	Create                 bool   `config:"create"`
+	NumGoroutines          int    `config:"num_goroutines"`
+	MaxOutstandingMessages int    `config:"max_outstanding_messages"`
+
+	// EnableMessageOrdering is only applied when Create is true.
+	EnableMessageOrdering bool `config:"enable_message_ordering"`
+}
+
+// DeliveryConfig configures how the input handles messages that keep
+// failing to reach the output instead of retrying them forever.
+type DeliveryConfig struct {
+	// MaxDeliveryAttempts is the number of times Pub/Sub may redeliver a
+	// message, read from msg.DeliveryAttempt, before the input treats it as
+	// exhausted. Zero (the default) disables the check and messages are
+	// nacked and redelivered indefinitely. Pub/Sub only populates
+	// DeliveryAttempt for subscriptions that have a DeadLetterPolicy, so
+	// setting this also requires DeadLetterTopic and only takes effect when
+	// subscription.create is true.
+	MaxDeliveryAttempts int `config:"max_delivery_attempts"`
+
+	// DeadLetterTopic is where exhausted messages are published instead of
+	// being handed to the regular outlet. It is required whenever
+	// MaxDeliveryAttempts is set: it becomes the subscription's native
+	// pubsub.DeadLetterPolicy target, which is what makes Pub/Sub populate
+	// msg.DeliveryAttempt in the first place.
+	DeadLetterTopic string `config:"dead_letter_topic"`
+
+	// MinBackoff and MaxBackoff are only applied when subscription.create
+	// is true: they're propagated into the new subscription's
+	// pubsub.RetryPolicy.
+	MinBackoff time.Duration `config:"min_backoff"`
+	MaxBackoff time.Duration `config:"max_backoff"`
+}
+
+// PushConfig configures the optional push-subscription mode, where
+// Pub/Sub delivers messages to an HTTP(S) endpoint managed by this input
+// instead of the input pulling them itself.
+type PushConfig struct {
+	// Enabled switches the input from pull-based sub.Receive to serving
+	// an HTTP endpoint that Pub/Sub push subscriptions deliver to.
+	Enabled bool `config:"enabled"`
+
+	// Address is the bind address (host:port) for the push endpoint.
+	Address string `config:"address"`
+
+	// Path is the HTTP path that accepts push requests.
+	Path string `config:"path"`
+
+	// OIDCAudience, when set, requires the push request to carry a bearer
+	// JWT issued for this audience and verifies it against Google's OIDC
+	// discovery document before accepting the message.
+	OIDCAudience string `config:"oidc_audience"`
+
+	TLS *tlscommon.ServerConfig `config:"ssl"`
+}
+
+func defaultConfig() config {
+	return config{
+		Subscription: SubscriptionConfig{
+			NumGoroutines:          1,
+			MaxOutstandingMessages: 1000,
+		},
+		Push: PushConfig{
+			Path: "/",
+		},
+		Format: formatDefault,
+	}
+}
+
+// Validate checks that Format is one of the supported values. It is called
+// automatically by go-ucfg while unpacking the input's configuration.
+func (c *config) Validate() error {
+	switch c.Format {
+	case formatDefault, formatCloudEvents:
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q, expected %q", c.Format, formatCloudEvents)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// newTestPubsubClient starts an in-process Pub/Sub emulator and returns a
+// real *pubsub.Client connected to it, so getOrCreateSubscription and
+// handleExhaustedMessage can be exercised against the real client library
+// instead of a hand-rolled fake.
+func newTestPubsubClient(t *testing.T) *pubsub.Client {
+	t.Helper()
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.Dial(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial pstest server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	client, err := pubsub.NewClient(context.Background(), "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create pubsub client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestDeliveryExhausted(t *testing.T) {
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test")}
+	in.metrics = newInputMetrics("delivery_test-exhausted", nil)
+	defer in.metrics.Close()
+
+	attempt := 10
+	if in.deliveryExhausted(&pubsub.Message{DeliveryAttempt: &attempt}) {
+		t.Error("expected deliveryExhausted to be false when max_delivery_attempts is disabled")
+	}
+
+	in.Delivery.MaxDeliveryAttempts = 5
+
+	if in.deliveryExhausted(&pubsub.Message{}) {
+		t.Error("expected deliveryExhausted to be false when DeliveryAttempt is unavailable")
+	}
+
+	under := 3
+	if in.deliveryExhausted(&pubsub.Message{DeliveryAttempt: &under}) {
+		t.Error("expected deliveryExhausted to be false below the threshold")
+	}
+
+	over := 6
+	if !in.deliveryExhausted(&pubsub.Message{DeliveryAttempt: &over}) {
+		t.Error("expected deliveryExhausted to be true above the threshold")
+	}
+}
+
+// receiveOne pulls a single live message back out of sub, so
+// handleExhaustedMessage can be called with a message that has a working
+// Ack/Nack handler.
+func receiveOne(t *testing.T, ctx context.Context, sub *pubsub.Subscription) *pubsub.Message {
+	t.Helper()
+
+	recvCtx, cancel := context.WithCancel(ctx)
+	received := make(chan *pubsub.Message, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := sub.Receive(recvCtx, func(_ context.Context, msg *pubsub.Message) {
+			received <- msg
+		}); err != nil && recvCtx.Err() == nil {
+			t.Errorf("sub.Receive failed: %v", err)
+		}
+	}()
+
+	select {
+	case msg := <-received:
+		cancel()
+		<-done
+		return msg
+	case <-time.After(5 * time.Second):
+		cancel()
+		<-done
+		t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+func TestHandleExhaustedMessageFallsBackToOutlet(t *testing.T) {
+	ctx := context.Background()
+	client := newTestPubsubClient(t)
+
+	topic, err := client.CreateTopic(ctx, "my-topic")
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := client.CreateSubscription(ctx, "my-sub", pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("hello")}).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	outlet := &fakeOutleter{accept: true}
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test"), outlet: outlet}
+	in.ProjectID = "test-project"
+	in.Topic = "my-topic"
+	in.metrics = newInputMetrics("delivery_test-fallback", nil)
+	defer in.metrics.Close()
+
+	msg := receiveOne(t, ctx, sub)
+	topicID := makeTopicID(in.ProjectID, in.Topic)
+	in.handleExhaustedMessage(ctx, client, topicID, msg)
+
+	if len(outlet.events) != 1 {
+		t.Fatalf("expected the exhausted message to be handed to the outlet, got %d events", len(outlet.events))
+	}
+	kind, err := outlet.events[0].Fields.GetValue("event.kind")
+	if err != nil || kind != "dead_letter" {
+		t.Errorf("expected event.kind: dead_letter, got %v (err=%v)", kind, err)
+	}
+}
+
+func TestHandleExhaustedMessagePublishesToDeadLetterTopic(t *testing.T) {
+	ctx := context.Background()
+	client := newTestPubsubClient(t)
+
+	topic, err := client.CreateTopic(ctx, "my-topic")
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := client.CreateSubscription(ctx, "my-sub", pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	dlq, err := client.CreateTopic(ctx, "my-dlq")
+	if err != nil {
+		t.Fatalf("failed to create dead-letter topic: %v", err)
+	}
+	dlqSub, err := client.CreateSubscription(ctx, "my-dlq-sub", pubsub.SubscriptionConfig{Topic: dlq})
+	if err != nil {
+		t.Fatalf("failed to create dead-letter subscription: %v", err)
+	}
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("hello")}).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	outlet := &fakeOutleter{accept: true}
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test"), outlet: outlet}
+	in.ProjectID = "test-project"
+	in.Topic = "my-topic"
+	in.Delivery.DeadLetterTopic = "my-dlq"
+	in.metrics = newInputMetrics("delivery_test-deadletter", nil)
+	defer in.metrics.Close()
+
+	msg := receiveOne(t, ctx, sub)
+	topicID := makeTopicID(in.ProjectID, in.Topic)
+	in.handleExhaustedMessage(ctx, client, topicID, msg)
+
+	if len(outlet.events) != 0 {
+		t.Errorf("expected the exhausted message to bypass the outlet when a dead-letter topic is set, got %d events", len(outlet.events))
+	}
+	if got := in.metrics.deadLetteredMessageCount.Get(); got != 1 {
+		t.Errorf("expected dead_lettered_message_count to be 1, got %d", got)
+	}
+
+	dlqMsg := receiveOne(t, ctx, dlqSub)
+	if string(dlqMsg.Data) != "hello" {
+		t.Errorf("expected the dead-letter topic to receive the original payload, got %q", dlqMsg.Data)
+	}
+}
+
+func TestGetOrCreateSubscriptionRequiresDeadLetterTopic(t *testing.T) {
+	ctx := context.Background()
+	client := newTestPubsubClient(t)
+
+	if _, err := client.CreateTopic(ctx, "my-topic"); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test")}
+	in.Topic = "my-topic"
+	in.Subscription = SubscriptionConfig{Name: "my-sub", Create: true}
+	in.Delivery = DeliveryConfig{MaxDeliveryAttempts: 7}
+
+	if _, err := in.getOrCreateSubscription(ctx, client); err == nil {
+		t.Fatal("expected an error when dead_letter_topic is unset but max_delivery_attempts is set")
+	}
+}
+
+func TestGetOrCreateSubscriptionSetsDeadLetterPolicy(t *testing.T) {
+	ctx := context.Background()
+	client := newTestPubsubClient(t)
+
+	if _, err := client.CreateTopic(ctx, "my-topic"); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	if _, err := client.CreateTopic(ctx, "my-dlq"); err != nil {
+		t.Fatalf("failed to create dead-letter topic: %v", err)
+	}
+
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test")}
+	in.Topic = "my-topic"
+	in.Subscription = SubscriptionConfig{Name: "my-sub", Create: true}
+	in.Delivery = DeliveryConfig{MaxDeliveryAttempts: 2, DeadLetterTopic: "my-dlq"}
+
+	sub, err := in.getOrCreateSubscription(ctx, client)
+	if err != nil {
+		t.Fatalf("getOrCreateSubscription failed: %v", err)
+	}
+
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch subscription config: %v", err)
+	}
+	if cfg.DeadLetterPolicy == nil {
+		t.Fatal("expected a DeadLetterPolicy to be set")
+	}
+	if cfg.DeadLetterPolicy.MaxDeliveryAttempts != minDeadLetterDeliveryAttempts {
+		t.Errorf("expected MaxDeliveryAttempts to be clamped to %d, got %d", minDeadLetterDeliveryAttempts, cfg.DeadLetterPolicy.MaxDeliveryAttempts)
+	}
+}
+
+func TestGetOrCreateSubscriptionAppliesRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	client := newTestPubsubClient(t)
+
+	if _, err := client.CreateTopic(ctx, "my-topic"); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	in := &pubsubInput{log: logp.NewLogger("gcppubsub_test")}
+	in.Topic = "my-topic"
+	in.Subscription = SubscriptionConfig{Name: "my-sub", Create: true}
+	in.Delivery = DeliveryConfig{MinBackoff: 10 * time.Second, MaxBackoff: 60 * time.Second}
+
+	sub, err := in.getOrCreateSubscription(ctx, client)
+	if err != nil {
+		t.Fatalf("getOrCreateSubscription failed: %v", err)
+	}
+
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch subscription config: %v", err)
+	}
+	if cfg.RetryPolicy == nil {
+		t.Fatal("expected a RetryPolicy to be set")
+	}
+	if cfg.RetryPolicy.MinimumBackoff != 10*time.Second || cfg.RetryPolicy.MaximumBackoff != 60*time.Second {
+		t.Errorf("expected the configured backoffs to be applied, got %+v", cfg.RetryPolicy)
+	}
+}
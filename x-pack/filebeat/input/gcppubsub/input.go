@@ -43,6 +43,10 @@ const (
 
 	// retryInterval is the minimum duration between pub/sub client retries.
 	retryInterval = 30 * time.Second
+
+	// minDeadLetterDeliveryAttempts is the smallest MaxDeliveryAttempts the
+	// Pub/Sub API accepts on a DeadLetterPolicy.
+	minDeadLetterDeliveryAttempts = 5
 )
 
 func init() {
@@ -155,13 +159,19 @@ func NewInput(cfg *conf.C, connector channel.Connector, inputContext input.Conte
 		EventListener: acker.ConnectionOnly(
 			acker.EventPrivateReporter(func(_ int, privates []interface{}) {
 				for _, priv := range privates {
-					if msg, ok := priv.(*pubsub.Message); ok {
-						msg.Ack()
+					switch v := priv.(type) {
+					case *pubsub.Message:
+						v.Ack()
 
 						in.metrics.ackedMessageCount.Inc()
-						in.metrics.bytesProcessedTotal.Add(uint64(len(msg.Data)))
-						in.metrics.processingTime.Update(time.Since(msg.PublishTime).Nanoseconds())
-					} else {
+						in.metrics.bytesProcessedTotal.Add(uint64(len(v.Data)))
+						in.metrics.processingTime.Update(time.Since(v.PublishTime).Nanoseconds())
+					case *pushDelivery:
+						in.metrics.ackedMessageCount.Inc()
+						in.metrics.bytesProcessedTotal.Add(uint64(v.bytes))
+						in.metrics.processingTime.Update(time.Since(v.publishTime).Nanoseconds())
+						v.done <- true
+					default:
 						in.metrics.failedAckedMessageCount.Inc()
 						in.log.Error("Failed ACKing pub/sub event")
 					}
@@ -238,7 +248,18 @@ func (in *pubsubInput) Run() {
 	})
 }
 
+// run starts the input in either push or pull mode, depending on whether
+// push-subscription mode is configured.
 func (in *pubsubInput) run() error {
+	if in.Push.Enabled {
+		return in.runPush()
+	}
+	return in.runPull()
+}
+
+// runPull subscribes to the configured topic and receives messages with
+// sub.Receive until ctx is cancelled.
+func (in *pubsubInput) runPull() error {
 	ctx, cancel := context.WithCancel(in.workerCtx)
 	defer cancel()
 
@@ -264,7 +285,20 @@ func (in *pubsubInput) run() error {
 	// Start receiving messages.
 	topicID := makeTopicID(in.ProjectID, in.Topic)
 	err = sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		if ok := in.outlet.OnEvent(makeEvent(topicID, msg)); !ok {
+		if in.deliveryExhausted(msg) {
+			in.handleExhaustedMessage(ctx, client, topicID, msg)
+			return
+		}
+
+		event, err := in.buildEvent(topicID, msg)
+		if err != nil {
+			msg.Nack()
+			in.metrics.nackedMessageCount.Inc()
+			in.log.Errorw("Failed to decode pub/sub message.", "error", err)
+			return
+		}
+
+		if ok := in.outlet.OnEvent(event); !ok {
 			msg.Nack()
 			in.metrics.nackedMessageCount.Inc()
 			in.log.Debug("OnEvent returned false. Stopping input worker.")
@@ -300,6 +334,15 @@ func makeTopicID(project, topic string) string {
 	return prefix[:10]
 }
 
+// buildEvent converts msg into a beat.Event according to the input's
+// configured Format.
+func (in *pubsubInput) buildEvent(topicID string, msg *pubsub.Message) (beat.Event, error) {
+	if in.Format == formatCloudEvents {
+		return makeCloudEvent(topicID, msg)
+	}
+	return makeEvent(topicID, msg), nil
+}
+
 func makeEvent(topicID string, msg *pubsub.Message) beat.Event {
 	id := topicID + "-" + msg.ID
 
@@ -323,6 +366,56 @@ func makeEvent(topicID string, msg *pubsub.Message) beat.Event {
 	return event
 }
 
+// deliveryExhausted reports whether msg has been redelivered more times than
+// Delivery.MaxDeliveryAttempts allows. It always returns false when the
+// threshold is disabled (the default) or the delivery attempt isn't
+// available, which requires the subscription to have a DeadLetterPolicy
+// configured (see getOrCreateSubscription).
+func (in *pubsubInput) deliveryExhausted(msg *pubsub.Message) bool {
+	if in.Delivery.MaxDeliveryAttempts <= 0 || msg.DeliveryAttempt == nil {
+		return false
+	}
+	in.metrics.deliveryAttemptCount.Add(uint64(*msg.DeliveryAttempt))
+	return *msg.DeliveryAttempt > in.Delivery.MaxDeliveryAttempts
+}
+
+// handleExhaustedMessage removes a message that exceeded
+// Delivery.MaxDeliveryAttempts from the subscription: it is either
+// republished to Delivery.DeadLetterTopic, or handed to the regular outlet
+// tagged with event.kind: dead_letter when no dead-letter topic is
+// configured.
+func (in *pubsubInput) handleExhaustedMessage(ctx context.Context, client *pubsub.Client, topicID string, msg *pubsub.Message) {
+	in.metrics.deliveryAttemptsExhaustedCount.Inc()
+	in.log.Warnw("Pub/Sub message exceeded max delivery attempts.", "delivery_attempt", msg.DeliveryAttempt)
+
+	if in.Delivery.DeadLetterTopic == "" {
+		event, err := in.buildEvent(topicID, msg)
+		if err != nil {
+			msg.Nack()
+			in.log.Errorw("Failed to decode exhausted pub/sub message.", "error", err)
+			return
+		}
+		event.Fields.Put("event.kind", "dead_letter")
+
+		if ok := in.outlet.OnEvent(event); !ok {
+			msg.Nack()
+		}
+		return
+	}
+
+	topic := client.Topic(in.Delivery.DeadLetterTopic)
+	defer topic.Stop()
+
+	result := topic.Publish(ctx, &pubsub.Message{Data: msg.Data, Attributes: msg.Attributes})
+	if _, err := result.Get(ctx); err != nil {
+		in.log.Errorw("Failed to publish exhausted message to dead-letter topic.", "error", err)
+		msg.Nack()
+		return
+	}
+	msg.Ack()
+	in.metrics.deadLetteredMessageCount.Inc()
+}
+
 func (in *pubsubInput) getOrCreateSubscription(ctx context.Context, client *pubsub.Client) (*pubsub.Subscription, error) {
 	sub := client.Subscription(in.Subscription.Name)
 
@@ -336,9 +429,33 @@ func (in *pubsubInput) getOrCreateSubscription(ctx context.Context, client *pubs
 
 	// Create subscription.
 	if in.Subscription.Create {
-		sub, err = client.CreateSubscription(ctx, in.Subscription.Name, pubsub.SubscriptionConfig{
-			Topic: client.Topic(in.Topic),
-		})
+		subCfg := pubsub.SubscriptionConfig{
+			Topic:                 client.Topic(in.Topic),
+			EnableMessageOrdering: in.Subscription.EnableMessageOrdering,
+		}
+		if in.Delivery.MinBackoff > 0 || in.Delivery.MaxBackoff > 0 {
+			subCfg.RetryPolicy = &pubsub.RetryPolicy{
+				MinimumBackoff: in.Delivery.MinBackoff,
+				MaximumBackoff: in.Delivery.MaxBackoff,
+			}
+		}
+		if in.Delivery.MaxDeliveryAttempts > 0 {
+			if in.Delivery.DeadLetterTopic == "" {
+				return nil, errors.New("delivery.dead_letter_topic is required when delivery.max_delivery_attempts is set: " +
+					"Pub/Sub only populates msg.DeliveryAttempt for subscriptions with a DeadLetterPolicy, which requires a target topic")
+			}
+
+			maxAttempts := in.Delivery.MaxDeliveryAttempts
+			if maxAttempts < minDeadLetterDeliveryAttempts {
+				maxAttempts = minDeadLetterDeliveryAttempts
+			}
+			subCfg.DeadLetterPolicy = &pubsub.DeadLetterPolicy{
+				DeadLetterTopic:     client.Topic(in.Delivery.DeadLetterTopic).String(),
+				MaxDeliveryAttempts: maxAttempts,
+			}
+		}
+
+		sub, err = client.CreateSubscription(ctx, in.Subscription.Name, subCfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create subscription: %w", err)
 		}
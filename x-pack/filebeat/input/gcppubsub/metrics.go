@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-libs/monitoring/adapter"
+)
+
+// monitoringRegistry is the root registry that per-instance input metrics
+// are nested under, keyed by the input's ID.
+var monitoringRegistry = monitoring.Default.NewRegistry(inputName, monitoring.DoNotReport)
+
+// inputMetrics tracks counters for a single pubsubInput instance. It is
+// registered under the input's ID so that multiple instances of the input
+// don't clobber each other's metrics.
+type inputMetrics struct {
+	unregister func()
+
+	processingTime          metrics.Sample   // Histogram of time (ns) between a message's publish time and its ack.
+	ackedMessageCount       *monitoring.Uint // Number of messages acked.
+	failedAckedMessageCount *monitoring.Uint // Number of acks that failed because the private metadata was neither a pub/sub message nor a push delivery.
+	nackedMessageCount      *monitoring.Uint // Number of messages nacked because the outlet rejected them, the output did not ack them (push mode) or they failed to decode (pull mode).
+	bytesProcessedTotal     *monitoring.Uint // Number of bytes processed.
+
+	deliveryAttemptCount           *monitoring.Uint // Sum of msg.DeliveryAttempt across all messages checked against delivery.max_delivery_attempts, first deliveries included.
+	deliveryAttemptsExhaustedCount *monitoring.Uint // Number of messages that exceeded delivery.max_delivery_attempts.
+	deadLetteredMessageCount       *monitoring.Uint // Number of exhausted messages republished to delivery.dead_letter_topic.
+}
+
+// newInputMetrics returns an input metric for the given ID. If optionalParent
+// is nil the default monitoring registry is used.
+func newInputMetrics(id string, optionalParent *monitoring.Registry) *inputMetrics {
+	parent := optionalParent
+	if parent == nil {
+		parent = monitoringRegistry
+	}
+	reg := parent.NewRegistry(id)
+
+	out := &inputMetrics{
+		unregister:              func() { parent.Remove(id) },
+		processingTime:          metrics.NewUniformSample(1024),
+		ackedMessageCount:       monitoring.NewUint(reg, "acked_message_count"),
+		failedAckedMessageCount: monitoring.NewUint(reg, "failed_acked_message_count"),
+		nackedMessageCount:      monitoring.NewUint(reg, "nacked_message_count"),
+		bytesProcessedTotal:     monitoring.NewUint(reg, "bytes_processed_total"),
+
+		deliveryAttemptCount:           monitoring.NewUint(reg, "delivery_attempt_count"),
+		deliveryAttemptsExhaustedCount: monitoring.NewUint(reg, "delivery_attempts_exhausted_count"),
+		deadLetteredMessageCount:       monitoring.NewUint(reg, "dead_lettered_message_count"),
+	}
+	adapter.NewGoMetrics(reg, "processing_time", adapter.Accept).
+		Register("histogram", metrics.NewHistogram(out.processingTime))
+
+	return out
+}
+
+// Close removes this input's metrics from the monitoring registry.
+func (m *inputMetrics) Close() {
+	if m != nil && m.unregister != nil {
+		m.unregister()
+	}
+}
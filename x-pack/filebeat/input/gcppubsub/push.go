@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/idtoken"
+
+	"github.com/elastic/beats/v7/libbeat/management/status"
+)
+
+// pushRequest is the body Pub/Sub sends to a push subscription's endpoint.
+// See https://cloud.google.com/pubsub/docs/push#receive_push.
+type pushRequest struct {
+	Message      pushMessage `json:"message"`
+	Subscription string      `json:"subscription"`
+}
+
+type pushMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+}
+
+// pushDelivery stands in for the Private field that the pull path populates
+// with a *pubsub.Message: it lets the outlet's EventPrivateReporter report
+// the ack back to the HTTP handler that is blocked waiting to write a status
+// code, instead of calling msg.Ack() on a message that was never pulled
+// through the client library. The reporter only reports acks; an event the
+// output never acks leaves the handler waiting until the request ends.
+type pushDelivery struct {
+	bytes       int
+	publishTime time.Time
+	done        chan bool // Receives true once the output acks the event.
+}
+
+// runPush serves the configured push endpoint until ctx is cancelled. It
+// never touches the pubsub client library: Pub/Sub delivers messages to us
+// over HTTP and we signal success or failure with the response status code.
+func (in *pubsubInput) runPush() error {
+	ctx, cancel := context.WithCancel(in.workerCtx)
+	defer cancel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(in.Push.Path, in.servePush)
+	srv := &http.Server{
+		Addr:    in.Push.Address,
+		Handler: mux,
+	}
+
+	if in.Push.TLS.IsEnabled() {
+		tlsCfg, err := in.Push.TLS.BuildServerConfig("")
+		if err != nil {
+			return fmt.Errorf("failed to load push endpoint TLS config: %w", err)
+		}
+		srv.TLSConfig = tlsCfg
+	}
+
+	in.status.UpdateStatus(status.Running, "")
+	in.log.Infow("Starting Pub/Sub push endpoint.", "address", in.Push.Address, "path", in.Push.Path)
+
+	errCh := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errCh <- srv.ListenAndServeTLS("", "")
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			in.log.Warnw("Error shutting down push endpoint.", "error", err)
+		}
+		return ctx.Err()
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		in.status.UpdateStatus(status.Degraded, fmt.Sprintf("push endpoint failed: %v", err))
+		return err
+	}
+}
+
+// servePush handles a single Pub/Sub push delivery. It responds 2xx only
+// after the outlet has accepted the event and the event has been acked by
+// the output, and a non-2xx status otherwise so Pub/Sub retries delivery.
+func (in *pubsubInput) servePush(w http.ResponseWriter, r *http.Request) {
+	if in.Push.OIDCAudience != "" {
+		if err := verifyPushToken(r, in.Push.OIDCAudience); err != nil {
+			in.log.Warnw("Rejected push request with invalid token.", "error", err)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var body pushRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		in.log.Warnw("Failed to decode push request body.", "error", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.Message.Data)
+	if err != nil {
+		in.log.Warnw("Failed to decode push message data.", "error", err)
+		http.Error(w, "invalid message data", http.StatusBadRequest)
+		return
+	}
+
+	msg := &pubsub.Message{
+		ID:          body.Message.MessageID,
+		Data:        data,
+		Attributes:  body.Message.Attributes,
+		PublishTime: body.Message.PublishTime,
+	}
+
+	topicID := makeTopicID(in.ProjectID, in.Topic)
+	event, err := in.buildEvent(topicID, msg)
+	if err != nil {
+		in.log.Errorw("Failed to decode push message.", "error", err)
+		http.Error(w, "invalid message", http.StatusBadRequest)
+		return
+	}
+
+	pd := &pushDelivery{
+		bytes:       len(data),
+		publishTime: msg.PublishTime,
+		done:        make(chan bool, 1),
+	}
+	event.Private = pd
+
+	if ok := in.outlet.OnEvent(event); !ok {
+		in.metrics.nackedMessageCount.Inc()
+		in.log.Debug("OnEvent returned false for push message.")
+		http.Error(w, "input is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case acked := <-pd.done:
+		if acked {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			in.metrics.nackedMessageCount.Inc()
+			http.Error(w, "failed to process message", http.StatusInternalServerError)
+		}
+	case <-r.Context().Done():
+		in.metrics.nackedMessageCount.Inc()
+		http.Error(w, "request cancelled", http.StatusServiceUnavailable)
+	}
+}
+
+// verifyPushToken checks that r carries a bearer JWT issued by Google for
+// the configured audience, as Pub/Sub attaches when push authentication is
+// enabled on the subscription.
+func verifyPushToken(r *http.Request, audience string) error {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return errors.New("missing bearer token")
+	}
+
+	_, err := idtoken.Validate(r.Context(), strings.TrimPrefix(auth, prefix), audience)
+	return err
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package gcppubsub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// fakeOutleter is a minimal channel.Outleter that resolves a pushDelivery's
+// done channel according to accept, standing in for the ack that the real
+// outlet eventually reports back through EventPrivateReporter.
+type fakeOutleter struct {
+	accept bool
+	events []beat.Event
+}
+
+func (f *fakeOutleter) OnEvent(event beat.Event) bool {
+	f.events = append(f.events, event)
+	if pd, ok := event.Private.(*pushDelivery); ok {
+		pd.done <- f.accept
+	}
+	return f.accept
+}
+
+func (f *fakeOutleter) Done() <-chan struct{} { return nil }
+func (f *fakeOutleter) Close() error          { return nil }
+
+func newTestInput(outlet *fakeOutleter) *pubsubInput {
+	in := &pubsubInput{
+		log:    logp.NewLogger("gcppubsub_test"),
+		outlet: outlet,
+	}
+	in.ProjectID = "my-project"
+	in.Topic = "my-topic"
+	in.metrics = newInputMetrics("push_test-"+in.ProjectID+in.Topic, nil)
+	return in
+}
+
+func pushBody(t *testing.T, data string) []byte {
+	t.Helper()
+	body := pushRequest{
+		Message: pushMessage{
+			Data:      base64.StdEncoding.EncodeToString([]byte(data)),
+			MessageID: "1",
+		},
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal push body: %v", err)
+	}
+	return buf
+}
+
+func TestServePushAcksOnAccept(t *testing.T) {
+	outlet := &fakeOutleter{accept: true}
+	in := newTestInput(outlet)
+	defer in.metrics.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(pushBody(t, `{"hello":"world"}`)))
+	w := httptest.NewRecorder()
+
+	in.servePush(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if len(outlet.events) != 1 {
+		t.Fatalf("expected exactly one event to reach the outlet, got %d", len(outlet.events))
+	}
+}
+
+func TestServePushNacksOnReject(t *testing.T) {
+	outlet := &fakeOutleter{accept: false}
+	in := newTestInput(outlet)
+	defer in.metrics.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(pushBody(t, "payload")))
+	w := httptest.NewRecorder()
+
+	in.servePush(w, req)
+
+	if w.Code < 400 {
+		t.Fatalf("expected a non-2xx status so Pub/Sub retries delivery, got %d", w.Code)
+	}
+}
+
+func TestServePushRejectsInvalidRequestBody(t *testing.T) {
+	outlet := &fakeOutleter{accept: true}
+	in := newTestInput(outlet)
+	defer in.metrics.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("not json")))
+	w := httptest.NewRecorder()
+
+	in.servePush(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if len(outlet.events) != 0 {
+		t.Error("expected a malformed request to never reach the outlet")
+	}
+}
+
+func TestServePushRejectsInvalidMessageData(t *testing.T) {
+	outlet := &fakeOutleter{accept: true}
+	in := newTestInput(outlet)
+	defer in.metrics.Close()
+
+	buf := []byte(`{"message":{"data":"not-valid-base64!","messageId":"2"}}`)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(buf))
+	w := httptest.NewRecorder()
+
+	in.servePush(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestServePushRejectsMissingBearerToken(t *testing.T) {
+	outlet := &fakeOutleter{accept: true}
+	in := newTestInput(outlet)
+	in.Push.OIDCAudience = "https://example.com/push"
+	defer in.metrics.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(pushBody(t, "payload")))
+	w := httptest.NewRecorder()
+
+	in.servePush(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if len(outlet.events) != 0 {
+		t.Error("expected an unauthenticated request to never reach the outlet")
+	}
+}